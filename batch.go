@@ -0,0 +1,105 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// batchRow is one input file of the -batch-summary
+type batchRow struct {
+	file     string
+	title    string
+	emitted  int // Records emitted, or -1 if not counted, as for -xref
+	warnings int
+	failed   bool
+	parse    time.Duration // Time spent loading the file, for -profile-apis
+	generate time.Duration // Time spent generating its records, for -profile-apis
+}
+
+// Input files, in the order loaded
+var batch []*batchRow
+
+// Load each API file, recording its row of the -batch-summary
+// Under -keep-going, files which cannot be loaded are logged and skipped
+func load(files []string) []spec {
+	var apis []spec
+	for _, file := range files {
+		r := &batchRow{file: file, emitted: -1}
+		batch = append(batch, r)
+
+		before, start := warnings, time.Now()
+		api, err := f2api(file)
+		r.warnings = warnings - before
+		r.parse = time.Since(start)
+		if err != nil {
+			if !*keepGoing {
+				fatal("err:", err)
+			}
+
+			logLine("error", "err:", err)
+			r.failed = true
+			continue
+		}
+
+		api.summary = r
+		apis = append(apis, api)
+	}
+
+	return apis
+}
+
+// Finish a run once its output is written
+// Prints the -batch-summary and -profile-apis, then fails if -keep-going skipped any file
+func finish() {
+	if *profile > 0 && len(batch) > 0 {
+		profileAPIs()
+	}
+
+	if *batchSum && len(batch) > 0 {
+		w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "FILE\tTITLE\tEMITTED\tWARNINGS\tSTATUS")
+		for _, r := range batch {
+			emitted := "-"
+			if r.emitted >= 0 {
+				emitted = fmt.Sprint(r.emitted)
+			}
+			status := "ok"
+			if r.failed {
+				status = "failed"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", r.file, r.title, emitted, r.warnings, status)
+		}
+		w.Flush()
+	}
+
+	for _, r := range batch {
+		if r.failed {
+			os.Exit(1)
+		}
+	}
+}
+
+// Print the -profile-apis slowest input files by parse and generate time, to stderr
+func profileAPIs() {
+	slowest := append([]*batchRow(nil), batch...)
+	sort.SliceStable(slowest, func(i, j int) bool {
+		return slowest[i].parse+slowest[i].generate > slowest[j].parse+slowest[j].generate
+	})
+	if len(slowest) > *profile {
+		slowest = slowest[:*profile]
+	}
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tPARSE\tGENERATE\tTOTAL")
+	for _, r := range slowest {
+		fmt.Fprintf(w, "%s\t%v\t%v\t%v\n", r.file, r.parse.Round(time.Microsecond), r.generate.Round(time.Microsecond), (r.parse + r.generate).Round(time.Microsecond))
+	}
+	w.Flush()
+}
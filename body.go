@@ -0,0 +1,300 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/seh-msft/openapi"
+)
+
+// Media type of the request bodies expanded by -body
+const bodyType = "application/json"
+
+// Parameters for the properties of an operation's JSON request body, under -body
+// Under -explode-oneof, the properties of its oneOf and anyOf alternatives are included
+func (api spec) body(path, method string, operation openapi.Method) []use {
+	if !*bodyMode && !*explodeOne {
+		return nil
+	}
+
+	paths, _ := api.doc["paths"].(map[string]interface{})
+	methods, _ := paths[path].(map[string]interface{})
+	raw, _ := methods[method].(map[string]interface{})
+	if _, ok := raw["requestBody"]; !ok {
+		return nil
+	}
+
+	v, at := api.resolve(raw["requestBody"], jsonPointer("paths", path, method, "requestBody"))
+	requestBody, _ := v.(map[string]interface{})
+	required, _ := requestBody["required"].(bool)
+	content, _ := requestBody["content"].(map[string]interface{})
+	media, ok := content[bodyType].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	v, at = api.resolve(media["schema"], at+jsonPointer("content", bodyType, "schema"))
+	schema, _ := v.(map[string]interface{})
+
+	uses := api.properties(schema, at, required, "")
+	if *explodeOne {
+		uses = append(uses, api.alternatives(schema, at, required)...)
+	}
+
+	for i := range uses {
+		uses[i].path = path
+		uses[i].method = method
+		uses[i].operation = operation
+	}
+
+	return uses
+}
+
+// member is a property of a schema, as defined in it or in one of its allOf members
+type member struct {
+	v    interface{} // Definition, before following any $ref
+	at   string      // JSON Pointer to the definition
+	from string      // Name of the schema defining it, if any
+}
+
+// Parameters for the properties of an object schema, by name
+// A property is required if the body is required and the schema requires it
+func (api spec) properties(schema map[string]interface{}, at string, required bool, prefix string) []use {
+	props := make(map[string]member)
+	needed := make(map[string]bool)
+	api.collect(schema, at, props, needed, make(map[string]bool))
+
+	var names []string
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var uses []use
+	for i, name := range names {
+		m := props[name]
+		v, where := api.resolve(m.v, m.at)
+		prop, _ := v.(map[string]interface{})
+
+		// Shaped as a parameter object, for fields read from raw
+		raw := map[string]interface{}{"schema": prop}
+		for k, v := range prop {
+			raw[k] = v
+		}
+		raw["name"] = prefix + name
+		raw["in"] = "body"
+		raw["required"] = required && needed[name]
+
+		uses = append(uses, use{index: i, parameter: api.bodyParameter(raw, where), raw: raw, pointer: where, schema: m.from})
+	}
+
+	return uses
+}
+
+// Gather the properties and required names of a schema
+// Under -flatten-allof, those of its allOf members are merged in, the first definition of a property winning
+func (api spec) collect(schema map[string]interface{}, at string, props map[string]member, needed, seen map[string]bool) {
+	if seen[at] {
+		return
+	}
+	seen[at] = true
+
+	from := schemaName(schema, at)
+	own, _ := schema["properties"].(map[string]interface{})
+	for name, v := range own {
+		m := member{v, at + jsonPointer("properties", name), from}
+		if have, ok := props[name]; ok {
+			a, _ := api.resolve(have.v, have.at)
+			b, _ := api.resolve(m.v, m.at)
+			if !reflect.DeepEqual(a, b) {
+				warn("warn: allOf members", have.at, "and", m.at, "define", name, "differently, using the first")
+			}
+			continue
+		}
+		props[name] = m
+	}
+
+	list, _ := schema["required"].([]interface{})
+	for _, name := range list {
+		if name, ok := name.(string); ok {
+			needed[name] = true
+		}
+	}
+
+	if !*flattenAll {
+		return
+	}
+
+	all, _ := schema["allOf"].([]interface{})
+	for i, e := range all {
+		v, where := api.resolve(e, at+jsonPointer("allOf", strconv.Itoa(i)))
+		if obj, ok := v.(map[string]interface{}); ok {
+			api.collect(obj, where, props, needed, seen)
+		}
+	}
+}
+
+// Pattern of a JSON Pointer token naming a reusable schema
+var schemaToken = regexp.MustCompile(`/(?:components/schemas|definitions|\$defs)/([^/]+)`)
+
+// Name of the schema at a JSON Pointer, its title, else the nearest reusable schema enclosing it
+func schemaName(schema map[string]interface{}, at string) string {
+	if title, ok := schema["title"].(string); ok && len(title) > 0 {
+		return title
+	}
+
+	m := schemaToken.FindAllStringSubmatch(at, -1)
+	if len(m) < 1 {
+		return ""
+	}
+
+	token := m[len(m)-1][1]
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// Parameters for the properties of an object schema, recursing into nested objects
+// Nested properties are named by their dotted path, as "owner.name", and only leaves are included
+// seen holds the pointers of the enclosing schemas, to stop at recursive schemas
+func (api spec) nested(schema map[string]interface{}, at string, required bool, prefix string, seen map[string]bool) []use {
+	if seen[at] {
+		warn("warn: not expanding recursive schema", at, "in the API", api.Info.Title)
+		return nil
+	}
+
+	inner := map[string]bool{at: true}
+	for k := range seen {
+		inner[k] = true
+	}
+
+	var uses []use
+	for _, u := range api.properties(schema, at, required, prefix) {
+		prop, _ := u.raw["schema"].(map[string]interface{})
+		_, object := prop["properties"].(map[string]interface{})
+		_, composed := prop["allOf"].([]interface{})
+		if object || (composed && *flattenAll) {
+			uses = append(uses, api.nested(prop, u.pointer, u.parameter.Required, u.parameter.Name+operationSep, inner)...)
+			continue
+		}
+
+		uses = append(uses, u)
+	}
+
+	return uses
+}
+
+// Parameters for the properties of the oneOf and anyOf alternatives of a schema
+// With a discriminator, properties are prefixed by the discriminator value of their alternative
+// Otherwise, properties of the same name are merged, noting the alternatives they appear in
+func (api spec) alternatives(schema map[string]interface{}, at string, required bool) []use {
+	discriminator, _ := schema["discriminator"].(map[string]interface{})
+	mapping, _ := discriminator["mapping"].(map[string]interface{})
+
+	var uses []use
+	seen := make(map[string]int)
+	for _, key := range []string{"oneOf", "anyOf"} {
+		list, _ := schema[key].([]interface{})
+		for i, alt := range list {
+			v, where := api.resolve(alt, at+jsonPointer(key, strconv.Itoa(i)))
+			obj, _ := v.(map[string]interface{})
+			name := alternative(alt, obj, mapping, fmt.Sprint(key, i))
+
+			prefix := ""
+			if discriminator != nil {
+				prefix = name + operationSep
+			}
+
+			for _, u := range api.properties(obj, where, required, prefix) {
+				u.alternatives = []string{name}
+
+				j, ok := seen[u.parameter.Name]
+				if !ok {
+					seen[u.parameter.Name] = len(uses)
+					uses = append(uses, u)
+					continue
+				}
+
+				// Required if any alternative requires it
+				uses[j].alternatives = append(uses[j].alternatives, name)
+				uses[j].parameter.Required = uses[j].parameter.Required || u.parameter.Required
+				if uses[j].schema != u.schema {
+					uses[j].schema = ""
+				}
+			}
+		}
+	}
+
+	return uses
+}
+
+// Name of a oneOf or anyOf alternative, as its discriminator value
+// Unmapped referenced schemas are named by their last pointer token, as the discriminator default
+// Inline schemas are named by their title, else by fallback
+func alternative(alt interface{}, schema, mapping map[string]interface{}, fallback string) string {
+	obj, _ := alt.(map[string]interface{})
+	ref, _ := obj["$ref"].(string)
+	if ref == "" {
+		if title, ok := schema["title"].(string); ok && len(title) > 0 {
+			return title
+		}
+		return fallback
+	}
+
+	last := ref[strings.LastIndexAny(ref, "/#")+1:]
+
+	var values []string
+	for value := range mapping {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	for _, value := range values {
+		target, _ := mapping[value].(string)
+		if target == ref || target == last || strings.HasSuffix(ref, "/"+strings.TrimPrefix(target, "#/")) {
+			return value
+		}
+	}
+
+	return last
+}
+
+// Parameter for a body property shaped as a parameter object
+// Values are rendered as text, as openapi.Schema keeps only string defaults and enums
+func (api spec) bodyParameter(raw map[string]interface{}, at string) openapi.Parameter {
+	var parameter openapi.Parameter
+	parameter.Name, _ = raw["name"].(string)
+	parameter.In = "body"
+	parameter.Required, _ = raw["required"].(bool)
+	parameter.Description, _ = raw["description"].(string)
+
+	parameter.Schema.Type, _ = raw["type"].(string)
+	parameter.Schema.Default = literal(raw["default"])
+	enums, _ := raw["enum"].([]interface{})
+	for _, e := range enums {
+		parameter.Schema.Enums = append(parameter.Schema.Enums, literal(e))
+	}
+
+	if items, ok := raw["items"]; ok {
+		v, _ := api.resolve(items, at+jsonPointer("items"))
+		items, _ := v.(map[string]interface{})
+		parameter.Schema.Items.Type, _ = items["type"].(string)
+	}
+
+	return parameter
+}
+
+// Follow the $refs of a request body object from the JSON Pointer at
+func (api spec) resolve(v interface{}, at string) (interface{}, string) {
+	v, where, err := derefAt(api.doc, v, at)
+	if err != nil {
+		fatal("err: could not resolve", at, "of the API", api.Info.Title, "→", err)
+	}
+
+	return v, where
+}
@@ -5,29 +5,95 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/seh-msft/cfg"
-	"github.com/seh-msft/openapi"
 )
 
 var (
 	mkMode     = flag.Bool("mk", false, "Generate a new cfg file (default)")
 	jsonMode   = flag.Bool("json", false, "Convert a cfg file to JSON")
+	mdMode     = flag.Bool("md", false, "Emit a Markdown table of identifiers rather than a cfg (mk)")
 	cfgFile    = flag.String("cfg", "", "Input .cfg file (json)")
+	mergeMode  = flag.Bool("merge", false, "Merge records sharing a key across the -cfg and argument cfg files")
+	rootKey    = flag.String("json-root-key", "", "Wrap structured JSON records under this key of an object with a meta object, implies -structured (json)")
+	strategy   = flag.String("merge-strategy", "concat", "How -merge and -update combine the constraints of records sharing a key: concat, union, or deep")
+	structure  = flag.Bool("structured", false, "Emit JSON as an array of records rather than a string of cfg text (json)")
+	against    = flag.String("validate-against", "", "Check the records of a cfg file against the parameters of a spec, failing on drift")
+	hoistMode  = flag.Bool("hoist-constraints", false, "Report constraint blocks repeated across records, of generated output on stderr or of a -cfg file")
+	onlyRules  = flag.Bool("only-constraints", false, "Emit only constraint lines under a comment naming their identifier, from generation or -cfg")
+	stripMode  = flag.Bool("strip-comments", false, "Re-emit a cfg file without its comments")
 	apiFile    = flag.String("api", "", "Input .json OpenAPI specification file (mk)")
 	outFile    = flag.String("o", "", "Output file")
 	strict     = flag.Bool("strict", false, "Generate a strict cfg allowlisting explicit path:title combinations (mk)")
 	everything = flag.Bool("all", false, "Include every parameter in the output (mk)")
 	useSingle  = flag.Bool("single", false, "Force usage of single quoting")
 	noAPI      = flag.Bool("minimal", false, "If not in strict mode, do not emit exclusivity parameters (mk)")
-	cautious   = flag.Bool("cautious", false, "")
+	cautious   = flag.Bool("cautious", false, "Quote every value, as -quote-all, and quote the patterns of loose disallow constraints (mk)")
+	quoteAll   = flag.Bool("quote-all", false, "Quote every name and value, leaving constraint templates as-is")
+	order      = flag.String("constraint-order", "disallow-first", "Order of each record's constraints: disallow-first or permit-first (mk)")
+	foldCase   = flag.Bool("fold-case-constraints", false, "Emit case-insensitive (?i) path and title constraint patterns (mk)")
+	injectFile = flag.String("inject", "", "Rewrite only the generated block between markers of this cfg file (mk)")
+	writeDiff  = flag.Bool("write-if-changed", false, "Only write the -o file if its content would change, leaving it untouched otherwise")
+	doUpdate   = flag.Bool("update", false, "Append newly generated identifiers to the existing -o file, keeping existing records (mk)")
+	changeFile = flag.String("changelog", "", "Write an -update changelog to a file, - for stderr (mk)")
+	baseDir    = flag.String("base-dir", "", "Directory relative external $refs are resolved against, rather than the spec's own (mk)")
+	deadline   = flag.Duration("deadline", 0, "Abandon the whole run if it takes longer than this, such as 30s")
+	refDepth   = flag.Int("ref-depth", 32, "Maximum length of a $ref chain to follow (mk)")
+	logFormat  = flag.String("log-format", "text", "Format of warnings and errors, text or json")
+	logFile    = flag.String("log-file", "", "Append warnings and errors to a file rather than stderr")
+	specFormat = flag.String("format", "", "Specification format if it cannot be detected from content, json or yaml (mk)")
+	validUTF8  = flag.Bool("validate-utf8", false, "Reject input files which are not valid UTF-8")
+	dryRun     = flag.Bool("dry-validate", false, "Generate and validate a cfg in memory without writing output (mk)")
+	examples   = flag.Bool("examples", false, "Fill record values from parameter examples, or schema defaults (mk)")
+	exampleID  = flag.String("example-name", "", "Named entry of a parameter's examples map to prefer for -examples (mk)")
+	deprecated = flag.String("deprecated-suffix", "", "Suffix appended to identifiers of deprecated parameters, such as _deprecated (mk)")
+	arraySufx  = flag.String("array-suffix", "", "Suffix appended to identifiers of array parameters, such as [] or _list (mk)")
+	types      = flag.Bool("types", false, "Emit comments with each record's schema type, and the schema of body properties (mk)")
+	onlyNames  = flag.String("only", "", "Comma separated parameter names to emit, omitting others (mk)")
+	omitNames  = flag.String("exclude", "", "Comma separated parameter names to omit (mk)")
+	tags       = flag.String("tag", "", "Comma separated operation tags, emitting only parameters of tagged operations (mk)")
+	reportIdle = flag.Bool("report-unused-filters", false, "Warn of -only, -exclude, and -tag values which matched nothing (mk)")
+	opComments = flag.Bool("operation-comments", false, "Emit the originating operationId, or method and path, as a comment on each record (strict)")
+	titleMode  = flag.String("title-transform", "as-is", "Transform API titles for headers and constraints: as-is, slug, upper, or lower (mk)")
+	stats      = flag.Bool("stats", false, "Count required, optional, and emitted identifiers in each API's header comment (mk)")
+	unicodeNFC = flag.Bool("normalize-unicode", false, "Apply NFC to identifier names and strip control and zero-width characters (mk)")
+	bounds     = flag.Bool("item-bounds", false, "Emit min-items and max-items constraints for array parameters with minItems or maxItems (mk)")
+	stripBase  = flag.Bool("strip-base-path", false, "Remove the longest matching servers[].url path from paths in constraints (strict)")
+	namespace  = flag.String("namespace", "", "Reverse-DNS prefix joined to each identifier with dots, such as com.example.api (mk)")
+	opKeys     = flag.Bool("key-by-operation", false, "Prefix identifiers with their operationId, or method_path (mk)")
+	fromEnv    = flag.Bool("fill-from-env", false, "Fill record values from environment variables named by their uppercased identifiers (mk)")
+	envPrefix  = flag.String("env-prefix", "", "Prefix of the environment variables read by -fill-from-env, such as APP_ (mk)")
+	samples    = flag.Bool("sample-values", false, "Fill empty record values with placeholders for their schema type (mk)")
+	withPtr    = flag.Bool("with-pointer", false, "Give the JSON Pointer of each parameter in the spec in -xref output (mk)")
+	treeMode   = flag.Bool("tree", false, "Emit a tree of each API's paths, methods, and parameters, rather than a cfg (mk)")
+	xref       = flag.Bool("xref", false, "Emit a JSON index of identifiers to the endpoints using them, rather than a cfg (mk)")
+	schemaMode = flag.Bool("schema", false, "Input files are JSON Schemas rather than specs, emitting an identifier per property (mk)")
+	bodyMode   = flag.Bool("body", false, "Emit identifiers for the properties of JSON request bodies (mk)")
+	flattenAll = flag.Bool("flatten-allof", false, "Merge the properties of allOf members into the schemas expanded by -body and -schema (mk)")
+	explodeOne = flag.Bool("explode-oneof", false, "Also emit identifiers for the properties of oneOf and anyOf request body alternatives, implies -body (mk)")
+	extensions = flag.String("x-extensions", "", "Comma separated operation vendor extensions to harvest identifiers from, such as x-rate-limit (mk)")
+	threshold  = flag.Int("param-threshold", 0, "Warn of operations with more than this many emitted parameters, 0 for off (mk)")
+	needDesc   = flag.Bool("require-description", false, "Fail if an emitted parameter has no description (mk)")
+	wError     = flag.Bool("warnings-as-errors", false, "Treat warnings as fatal errors")
+	needTitle  = flag.Bool("fail-on-missing-title", false, "Fail on an API without info.title rather than using its file name (mk)")
+	batchSum   = flag.Bool("batch-summary", false, "Print a table of each input file's title, records, warnings, and status to stderr at the end (mk)")
+	profile    = flag.Int("profile-apis", 0, "Print the N input files slowest to parse and generate to stderr at the end (mk)")
+	keepGoing  = flag.Bool("keep-going", false, "Skip input files which cannot be loaded rather than stopping, failing at the end (mk)")
+	verbose    = flag.Bool("verbose", false, "Log informational messages")
 	quote      = '"'
 )
 
@@ -35,29 +101,212 @@ var (
 func main() {
 	flag.Parse()
 	args := flag.Args()
+	openLog()
+	checkStrategy()
+	startDeadline()
+	defer finish()
+
+	if *useSingle {
+		quote = '\''
+	}
+
+	// Existing output must be read before the output file is replaced
+	var existing []byte
+	if *doUpdate {
+		if len(*outFile) < 1 {
+			fatal("err: -update requires -o")
+		}
+
+		var err error
+		existing, err = os.ReadFile(*outFile)
+		if err != nil && !os.IsNotExist(err) {
+			fatal("err: could not read output file →", err)
+		}
+		if err := checkUTF8(*outFile, existing); err != nil {
+			fatal("err:", err)
+		}
+	}
+
+	// Validate generated output in memory, never touching the filesystem
+	if *dryRun {
+		var generated strings.Builder
+		mk(args, &generated)
+		if _, err := cfg.Load(strings.NewReader(generated.String())); err != nil {
+			fatal("err: generated cfg is invalid →", err)
+		}
+		return
+	}
+
+	// Only the generated block of the inject file is rewritten
+	if len(*injectFile) > 0 {
+		var generated strings.Builder
+		mk(args, &generated)
+		inject(*injectFile, generated.String())
+		return
+	}
 
 	// Output file handling
+	// An output file is generated in memory and only replaced once generation finishes
 	var out *bufio.Writer = bufio.NewWriter(os.Stdout)
+	var pending *bytes.Buffer
 	if len(*outFile) > 0 {
-		f, err := os.Create(*outFile)
-		if err != nil {
-			fatal("err: could not open output file →", err)
+		pending = new(bytes.Buffer)
+		out = bufio.NewWriter(pending)
+	}
+	defer func() {
+		progress("writing output")
+		out.Flush()
+		if pending != nil {
+			writeOutput(*outFile, pending.Bytes())
+		}
+	}()
+
+	if *onlyRules && len(*cfgFile) > 0 {
+		loadedPolicy(cfgInput(args), out)
+		return
+	}
+
+	if len(*against) > 0 {
+		validateAgainst(cfgInput(args))
+		return
+	}
+
+	// Constraint duplication is reported on stderr alongside generated output
+	if *hoistMode {
+		if len(*cfgFile) > 0 {
+			hoist(cfgInput(args), out)
+			return
 		}
-		out = bufio.NewWriter(f)
-		defer f.Close()
+
+		var generated strings.Builder
+		mk(args, &generated)
+		out.WriteString(generated.String())
+		hoist([]byte(generated.String()), os.Stderr)
+		return
+	}
+
+	if *stripMode {
+		stripComments(cfgInput(args), out)
+		return
 	}
-	defer out.Flush()
 
 	if *jsonMode && !*mkMode {
 		toJSON(args, out)
 		return
 	}
 
+	if *mergeMode {
+		format(mergeInputs(args), out)
+		return
+	}
+
+	if *doUpdate {
+		var generated strings.Builder
+		mk(args, &generated)
+		update(existing, generated.String(), out)
+		return
+	}
+
 	mk(args, out)
 }
 
 // Convert a cfg file to valid JSON
 func toJSON(args []string, out *bufio.Writer) {
+	var c cfg.Cfg
+	if *mergeMode {
+		c = mergeInputs(args)
+	} else {
+		var err error
+		c, err = cfg.Load(bytes.NewReader(cfgInput(args)))
+		if err != nil {
+			fatal("err: could not cfg parse file →", err)
+		}
+	}
+
+	if *structure || len(*rootKey) > 0 {
+		structured(c, out)
+		return
+	}
+
+	quoting := cfg.Double
+	if *useSingle {
+		quoting = cfg.Single
+	}
+
+	// Encode to JSON
+	var buf strings.Builder
+	emit(c, quoting, &buf)
+	enc := json.NewEncoder(out)
+	err := enc.Encode(buf.String())
+	if err != nil {
+		fatal("err: could not encode to JSON →", err)
+	}
+}
+
+// Write the output file once generation has finished
+// A regular file, or a new one, is written through a temporary file renamed over it, so an interrupted write leaves it as it was
+// Anything else, such as a device or symlink, is opened and written in place, so it is not replaced
+// Under -write-if-changed, an unchanged file is not touched, keeping its modification time
+func writeOutput(path string, data []byte) {
+	if old, err := os.ReadFile(path); *writeDiff && err == nil && bytes.Equal(old, data) {
+		info("info: output file unchanged →", path)
+		return
+	}
+
+	st, err := os.Lstat(path)
+	switch {
+	case err == nil && st.Mode().IsRegular():
+		replace(path, data, st.Mode().Perm())
+
+	case os.IsNotExist(err):
+		replace(path, data, 0644)
+
+	default:
+		f, err := os.Create(path)
+		if err != nil {
+			fatal("err: could not open output file →", err)
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fatal("err: could not write output file →", err)
+		}
+	}
+
+	if *writeDiff {
+		logLine("info", "info: wrote output file →", path)
+	}
+}
+
+// Replace a regular file by renaming a temporary file beside it over it
+func replace(path string, data []byte, mode os.FileMode) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		fatal("err: could not open output file →", err)
+	}
+	scratch(f.Name())
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		fatal("err: could not write output file →", err)
+	}
+	scratch("")
+}
+
+// Read the input cfg file, from -cfg or the first argument
+func cfgInput(args []string) []byte {
 	if (len(args) > 0 && len(*cfgFile) > 0) || (len(args) <= 0 && *cfgFile == "") {
 		fatal("err: one of -cfg or an argument file must be provided")
 	}
@@ -67,137 +316,301 @@ func toJSON(args []string, out *bufio.Writer) {
 		path = args[0]
 	}
 
-	f, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		fatal("err: could not open file →", err)
 	}
-
-	if *useSingle {
-		cfg.Quoting = cfg.Single
-	} else {
-		cfg.Quoting = cfg.Double
-	}
-	c, err := cfg.Load(f)
-	if err != nil {
-		fatal("err: could not cfg parse file →", err)
+	if err := checkUTF8(path, data); err != nil {
+		fatal("err:", err)
 	}
 
-	// Encode to JSON
-	var buf strings.Builder
-	c.Emit(&buf)
-	enc := json.NewEncoder(out)
-	err = enc.Encode(buf.String())
-	if err != nil {
-		fatal("err: could not encode to JSON →", err)
-	}
+	return data
+}
+
+// Serializes use of cfg.Quoting, which the cfg package reads globally when emitting
+var quotingMu sync.Mutex
+
+// Emit a cfg with a quoting mode, without racing concurrent emitters
+func emit(c cfg.Cfg, quoting cfg.Quotation, w io.Writer) {
+	quotingMu.Lock()
+	defer quotingMu.Unlock()
+
+	prev := cfg.Quoting
+	cfg.Quoting = quoting
+	defer func() { cfg.Quoting = prev }()
+
+	c.Emit(w)
 }
 
 // Generate a new cfg file for one or more OpenAPI specifications
 // Build a valid .cfg for all required identifiers in an OpenAPI specification
 // One API can be specified via -i or a variable number can be passed as arguments
-func mk(args []string, out *bufio.Writer) {
+func mk(args []string, out io.Writer) {
 	if (len(args) > 0 && len(*apiFile) > 0) || (len(args) <= 0 && *apiFile == "") {
 		fatal("err: one of -api or a list of argument specification files must be provided")
 	}
 
 	// Input file handling
-	var apis []openapi.API
-
+	files := args
 	if len(*apiFile) > 0 {
 		// One file
-		apis = append(apis, f2api(*apiFile))
+		files = []string{*apiFile}
+	}
+	apis := load(files)
 
-	} else {
-		// Many files as arguments
-		for _, file := range args {
-			apis = append(apis, f2api(file))
-		}
+	for i := range apis {
+		apis[i].Info.Title = transformTitle(apis[i].Info.Title)
+		apis[i].summary.title = apis[i].Info.Title
 	}
 
-	if *useSingle {
-		quote = '\''
+	setupFilters()
+
+	if !bare(*deprecated) || commented(*deprecated) {
+		fatal("err: -deprecated-suffix must not need quoting →", *deprecated)
 	}
+	if !bare(*arraySufx) || commented(*arraySufx) {
+		fatal("err: -array-suffix must not need quoting →", *arraySufx)
+	}
+	checkNamespace()
 
-	var do func(api openapi.API, out io.Writer) = doLoose
+	if *schemaMode && *strict {
+		fatal("err: -schema properties have no paths for -strict")
+	}
+	if *schemaMode && *opKeys {
+		fatal("err: -schema properties have no operations for -key-by-operation")
+	}
+
+	if *needDesc {
+		requireDescriptions(apis)
+	}
+
+	if *threshold > 0 {
+		paramThreshold(apis)
+	}
+
+	var do func(api spec, out io.Writer) int = doLoose
 	if *strict {
 		do = doStrict
 	}
 
-	for _, api := range apis {
-		do(api, out)
+	switch {
+	case *xref:
+		xrefs(apis, out)
+
+	case *mdMode:
+		markdown(apis, out)
+
+	case *treeMode:
+		tree(apis, out)
+
+	default:
+		for i, api := range apis {
+			progress(fmt.Sprintf("generating the API %s (%d of %d)", api.Info.Title, i+1, len(apis)))
+			before, start := warnings, time.Now()
+			api.summary.emitted = do(api, out)
+			api.summary.warnings += warnings - before
+			api.summary.generate = time.Since(start)
+		}
+	}
+
+	if *reportIdle {
+		reportFilters()
 	}
 }
 
-func doLoose(api openapi.API, out io.Writer) {
-	title := clean(api.Info.Title)
-	const tmpl = `%s=
-`
-	var constraints = `	disallow path=%c.*%c title=%c.*%c
-	permit title=%s
-`
+// Emit a record per identifier, permitted anywhere in the API, returning the number emitted
+func doLoose(api spec, out io.Writer) int {
+	pattern := clean(fold(api.Info.Title))
+
+	disallow := fmt.Sprintf("disallow path=%c.*%c title=%c.*%c", quote, quote, quote, quote)
 	if !*cautious {
-		constraints = `	disallow path=.* title=.*
-	permit title=%s
-`
+		disallow = "disallow path=.* title=.*"
 	}
+	permit := "permit title=" + pattern
 
-	fmt.Fprintf(out, "# Identifiers for the API %s:\n\n", title)
+	var constraints []string
+	if !*noAPI {
+		constraints = ordered(disallow, permit)
+	}
 
-	names := make(map[string]string)
-	for _, methods := range api.Paths {
-		for _, method := range methods {
-			for _, parameter := range method.Parameters {
-				if !parameter.Required && !*everything {
-					// Skip parameters that aren't required
-					continue
-				}
+	// Each name is emitted once, as first seen
+	var names []string
+	records := make(map[string]record)
+	walk(api, func(u use) {
+		name := clean(identifier(u))
+		if commented(name) {
+			warn("warn: skipping parameter", name, "of", u.path, "in the API", api.Info.Title, "as cfg reads # as a comment")
+			return
+		}
+		if _, ok := records[name]; ok {
+			return
+		}
 
-				names[clean(parameter.Name)] = ""
-			}
+		names = append(names, name)
+		records[name] = record{comments(u), name, valueText(api, u), append(constraints[:len(constraints):len(constraints)], itemBounds(u)...)}
+	})
+
+	header(out, api, len(names))
+	for _, name := range names {
+		// Emit identifiers
+		emitRecord(out, records[name])
+	}
+
+	return len(names)
+}
+
+// Emit a record per parameter, permitted at its path, returning the number emitted
+func doStrict(api spec, out io.Writer) int {
+	pattern := clean(fold(api.Info.Title))
+
+	disallow := fmt.Sprintf("disallow path=%c.*%c title=%c.*%c", quote, quote, quote, quote)
+	const permit = "permit path=%s title=%s"
+
+	var records []record
+	walk(api, func(u use) {
+		name := clean(identifier(u))
+		if commented(name) {
+			warn("warn: skipping parameter", name, "of", u.path, "in the API", api.Info.Title, "as cfg reads # as a comment")
+			return
 		}
+
+		constraints := ordered(disallow, fmt.Sprintf(permit, clean(fold(api.route(u.path))), pattern))
+		constraints = append(constraints, itemBounds(u)...)
+
+		notes := comments(u)
+		if *opComments {
+			notes = append(notes, "operation: "+operation(u))
+		}
+
+		records = append(records, record{notes, name, valueText(api, u), constraints})
+	})
+
+	header(out, api, len(records))
+	for _, r := range records {
+		emitRecord(out, r)
 	}
 
-	for name := range names {
-		// Emit identifiers
-		fmt.Fprintf(out, tmpl, name)
-		if !*noAPI {
-			if *cautious {
-				fmt.Fprintf(out, constraints, quote, quote, quote, quote, title)
+	return len(records)
+}
+
+// Emit the comment heading an API's identifiers
+// Under -stats, it counts the API's parameters passing the filters and the records emitted
+func header(out io.Writer, api spec, emitted int) {
+	fmt.Fprintf(out, "# Identifiers for the API %s:\n", clean(api.Info.Title))
+
+	if *stats {
+		required, optional := 0, 0
+		survey(api, func(u use) {
+			if u.parameter.Required {
+				required++
 			} else {
-				fmt.Fprintf(out, constraints, title)
+				optional++
 			}
-		}
+		})
+		fmt.Fprintf(out, "# %d required, %d optional, %d emitted\n", required, optional, emitted)
+	}
+
+	fmt.Fprintf(out, "\n")
+}
+
+// record is one generated identifier
+type record struct {
+	comments    []string // Comment lines above the record, without their '#'
+	name        string   // Cleaned identifier
+	value       string   // Cleaned value
+	constraints []string // Constraint lines, unindented
+}
 
-		fmt.Fprintf(out, "\n")
+// Emit a record's comments, name and value, its indented constraints, and a blank line
+func emitRecord(out io.Writer, r record) {
+	if *onlyRules {
+		policy(out, r.name, r.constraints)
+		return
+	}
+
+	for _, comment := range r.comments {
+		fmt.Fprintf(out, "# %s\n", comment)
 	}
+	fmt.Fprintf(out, "%s=%s\n", r.name, r.value)
+	for _, constraint := range r.constraints {
+		fmt.Fprintf(out, "\t%s\n", constraint)
+	}
+
+	fmt.Fprintf(out, "\n")
 }
 
-func doStrict(api openapi.API, out io.Writer) {
-	title := clean(api.Info.Title)
+// Whether a pattern matches anything
+func trivial(pattern string) bool {
+	pattern = strings.TrimSpace(pattern)
+	return pattern == "" || pattern == ".*"
+}
 
-	const tmpl = `%s=
-	disallow path=%c.*%c title=%c.*%c
-	permit path=%s title=%s
+// A record's disallow and permit lines, in the -constraint-order
+func ordered(disallow, permit string) []string {
+	switch *order {
+	case "disallow-first":
+		return []string{disallow, permit}
+	case "permit-first":
+		return []string{permit, disallow}
+	}
 
-`
+	fatal("err: unknown -constraint-order, must be disallow-first or permit-first →", *order)
+	return nil
+}
 
-	fmt.Fprintf(out, "# Identifiers for the API %s:\n\n", title)
+// Constraint lines bounding the length of an array parameter, under -item-bounds
+// Bounds absent from the schema are omitted
+func itemBounds(u use) []string {
+	if !*bounds || u.parameter.Schema.Type != "array" {
+		return nil
+	}
 
-	for path, methods := range api.Paths {
-		path = clean(path)
-		for _, method := range methods {
-			for _, parameter := range method.Parameters {
-				if !parameter.Required && !*everything {
-					// Skip parameters that aren't required
-					continue
-				}
+	schema, _ := u.raw["schema"].(map[string]interface{})
 
-				name := clean(parameter.Name)
+	var out []string
+	for _, b := range []struct{ key, attr string }{{"minItems", "min-items"}, {"maxItems", "max-items"}} {
+		if n, ok := schema[b.key]; ok {
+			out = append(out, b.attr+"="+clean(literal(n)))
+		}
+	}
 
-				fmt.Fprintf(out, tmpl, name, quote, quote, quote, quote, path, title)
-			}
+	return out
+}
+
+// Path an operation is seen at by the gateway, for constraints
+// Under -strip-base-path, the longest servers[].url path prefixing it is removed
+func (api spec) route(path string) string {
+	if !*stripBase {
+		return path
+	}
+
+	longest := ""
+	for _, server := range api.Servers {
+		u, err := url.Parse(server.URL)
+		if err != nil {
+			continue
+		}
+
+		base := strings.TrimSuffix(u.Path, "/")
+		if len(base) > len(longest) && (path == base || strings.HasPrefix(path, base+"/")) {
+			longest = base
 		}
 	}
+
+	if len(longest) > 0 && path == longest {
+		return "/"
+	}
+
+	return strings.TrimPrefix(path, longest)
+}
+
+// Prefix a constraint pattern so it matches case-insensitively, if requested
+func fold(pattern string) string {
+	if *foldCase {
+		return "(?i)" + pattern
+	}
+
+	return pattern
 }
 
 // Double quote escape quote literals, if any
@@ -205,37 +618,112 @@ func doStrict(api openapi.API, out io.Writer) {
 func clean(s string) string {
 
 	out := strings.ReplaceAll(s, string(quote), string(quote)+string(quote))
-	if *cautious {
+	if *cautious || *quoteAll {
 		return string(quote) + out + string(quote)
 	}
 
-	for _, rune := range out {
-		if unicode.IsSpace(rune) {
-			return string(quote) + out + string(quote)
-		}
+	if !bare(out) {
+		return string(quote) + out + string(quote)
 	}
 
 	return out
 }
 
+// Whether a string may be emitted unquoted
+// Whitespace, assignment, and quotes would be interpreted by cfg
+func bare(s string) bool {
+	for _, rune := range s {
+		if unicode.IsSpace(rune) || strings.ContainsRune(`='"`, rune) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Whether a string cannot be emitted at all
+// cfg reads a comment from any #, even within quotes, and has no escape for it
+func commented(s string) bool {
+	return strings.ContainsRune(s, '#')
+}
+
+// Apply the -title-transform to an API title
+func transformTitle(title string) string {
+	switch *titleMode {
+	case "as-is":
+		return title
+
+	case "upper":
+		return strings.ToUpper(title)
+
+	case "lower":
+		return strings.ToLower(title)
+
+	case "slug":
+		// Lowercase, with each run of other characters a single dash
+		words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		return strings.Join(words, "-")
+	}
+
+	fatal("err: unknown -title-transform, must be as-is, slug, upper, or lower →", *titleMode)
+	return title
+}
+
 // Open an API
-func f2api(path string) openapi.API {
-	f, err := os.Open(path)
+func f2api(path string) (spec, error) {
+	progress("parsing ", path)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		fatal("err: could not open API file →", err)
+		return spec{}, fmt.Errorf("could not open API file %s → %v", path, err)
+	}
+	if err := checkUTF8(path, data); err != nil {
+		return spec{}, err
 	}
-	defer f.Close()
 
-	api, err := openapi.Parse(f)
+	parse := parseAPI
+	if *schemaMode {
+		parse = parseSchema
+	}
+
+	api, err := parse(path, data)
 	if err != nil {
-		fatal("err: could not parse API →", err)
+		return spec{}, fmt.Errorf("could not parse API file %s → %v", path, err)
+	}
+
+	if len(api.Info.Title) < 1 {
+		if *needTitle {
+			return spec{}, errors.New("no info.title in API file → " + path)
+		}
+
+		// Permits need some title to match
+		api.Info.Title = filepath.Base(path)
+		warn("warn: no info.title in", path, "using the file name")
+	}
+
+	return api, nil
+}
+
+// Check an input file is valid UTF-8, if requested
+func checkUTF8(path string, data []byte) error {
+	if !*validUTF8 {
+		return nil
+	}
+
+	for i := 0; i < len(data); {
+		r, n := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && n == 1 {
+			return fmt.Errorf("invalid UTF-8 in %s at byte offset %d", path, i)
+		}
+		i += n
 	}
 
-	return api
+	return nil
 }
 
 // Fatal - end program with an error message and newline
 func fatal(s ...interface{}) {
-	fmt.Fprintln(os.Stderr, s...)
+	logLine("error", s...)
 	os.Exit(1)
 }
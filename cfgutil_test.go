@@ -0,0 +1,125 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/seh-msft/cfg"
+)
+
+// TestEmitConcurrent checks concurrent emitters each get their own quoting, run with -race
+func TestEmitConcurrent(t *testing.T) {
+	c, err := cfg.Load(strings.NewReader("name=\"two words\"\n"))
+	if err != nil {
+		t.Fatal("could not load →", err)
+	}
+
+	want := map[cfg.Quotation]string{
+		cfg.Single: `name='two words'`,
+		cfg.Double: `name="two words"`,
+	}
+
+	prev := cfg.Quoting
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		quoting := cfg.Single
+		if i%2 == 0 {
+			quoting = cfg.Double
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var out strings.Builder
+			emit(c, quoting, &out)
+			if !strings.Contains(out.String(), want[quoting]) {
+				t.Errorf("emitted %q, want %q", out.String(), want[quoting])
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cfg.Quoting != prev {
+		t.Error("cfg.Quoting not restored after emit")
+	}
+}
+
+// TestMissingTitle checks an untitled API takes its file name, or fails under -fail-on-missing-title
+func TestMissingTitle(t *testing.T) {
+	const path = "testdata/notitle.json"
+
+	var log strings.Builder
+	logOut = &log
+	defer func() { logOut = os.Stderr }()
+
+	api, err := f2api(path)
+	if err != nil {
+		t.Fatal("could not load", path, "→", err)
+	}
+	if api.Info.Title != "notitle.json" {
+		t.Errorf("title %q, want the file name", api.Info.Title)
+	}
+	if want := "warn: no info.title in " + path + " using the file name"; !strings.Contains(log.String(), want) {
+		t.Errorf("logged %q, want %q", log.String(), want)
+	}
+
+	*needTitle = true
+	defer func() { *needTitle = false }()
+
+	if _, err := f2api(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("got error %v, want one naming %s", err, path)
+	}
+}
+
+// TestItemBounds checks -item-bounds emits array bounds, which swagger 2.0 keeps outside the schema
+func TestItemBounds(t *testing.T) {
+	*bounds = true
+	defer func() { *bounds = false }()
+
+	for _, path := range []string{"testdata/bounded.json", "testdata/bounded-swagger.json"} {
+		api, err := f2api(path)
+		if err != nil {
+			t.Fatal("could not load", path, "→", err)
+		}
+
+		paths, _ := api.doc["paths"].(map[string]interface{})
+		methods, _ := paths["/pets"].(map[string]interface{})
+		get, _ := methods["get"].(map[string]interface{})
+		parameters, _ := get["parameters"].([]interface{})
+		parameter, _ := parameters[0].(map[string]interface{})
+		schema, _ := parameter["schema"].(map[string]interface{})
+		for _, key := range []string{"minItems", "maxItems"} {
+			if _, ok := parameter[key]; ok {
+				t.Errorf("%s: %s left on the parameter", path, key)
+			}
+			if _, ok := schema[key]; !ok {
+				t.Errorf("%s: %s missing from the schema", path, key)
+			}
+		}
+
+		var out strings.Builder
+		doStrict(api, &out)
+		for _, want := range []string{"\tmin-items=1\n", "\tmax-items=5\n"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s: emitted %q, want %q", path, out.String(), want)
+			}
+		}
+	}
+}
+
+// TestInvalidUTF8 checks -validate-utf8 fails an API file by error, so -keep-going can skip it
+func TestInvalidUTF8(t *testing.T) {
+	const path = "testdata/badutf8.json"
+
+	*validUTF8 = true
+	defer func() { *validUTF8 = false }()
+
+	if _, err := f2api(path); err == nil || !strings.Contains(err.Error(), "invalid UTF-8 in "+path) {
+		t.Errorf("got error %v, want one naming %s", err, path)
+	}
+}
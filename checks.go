@@ -0,0 +1,99 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/seh-msft/cfg"
+)
+
+// Fail if any parameter to be emitted lacks a description, listing every offender
+func requireDescriptions(apis []spec) {
+	n := 0
+	for _, api := range apis {
+		walk(api, func(u use) {
+			if strings.TrimSpace(u.parameter.Description) != "" {
+				return
+			}
+
+			logLine("error", "err: no description for", u.parameter.Name, "of", strings.ToUpper(u.method), u.path, "in the API", api.Info.Title)
+			n++
+		})
+	}
+
+	if n > 0 {
+		fatal("err:", n, "parameters lack a description")
+	}
+}
+
+// Warn of operations contributing more parameters than -param-threshold
+func paramThreshold(apis []spec) {
+	for _, api := range apis {
+		var endpoints []string
+		counts := make(map[string]int)
+		walk(api, func(u use) {
+			endpoint := strings.ToUpper(u.method) + " " + u.path
+			if counts[endpoint] == 0 {
+				endpoints = append(endpoints, endpoint)
+			}
+			counts[endpoint]++
+		})
+
+		for _, endpoint := range endpoints {
+			if n := counts[endpoint]; n > *threshold {
+				warn(fmt.Sprintf("warn: %s in the API %s has %d parameters, over -param-threshold %d", endpoint, api.Info.Title, n, *threshold))
+			}
+		}
+	}
+}
+
+// Cross-check the records of a cfg against the parameters of the -validate-against spec
+// Fails if the cfg has records for no parameter, or lacks records for required parameters
+func validateAgainst(data []byte) {
+	c, err := cfg.Load(bytes.NewReader(data))
+	if err != nil {
+		fatal("err: could not cfg parse file →", err)
+	}
+
+	api, err := f2api(*against)
+	if err != nil {
+		fatal("err:", err)
+	}
+	setupFilters()
+
+	known := make(map[string]bool)
+	survey(api, func(u use) {
+		known[identifier(u)] = true
+	})
+
+	have := make(map[string]bool)
+	n := 0
+	for _, r := range c.Records {
+		name := r.PrimaryKey()
+		have[name] = true
+		if !known[name] {
+			logLine("error", "err: record", name, "is not a parameter of the API", api.Info.Title)
+			n++
+		}
+	}
+
+	walk(api, func(u use) {
+		name := identifier(u)
+		if have[name] {
+			return
+		}
+
+		// Report each missing name once
+		have[name] = true
+		logLine("error", "err: no record for the required parameter", name, "of", strings.ToUpper(u.method), u.path)
+		n++
+	})
+
+	if n > 0 {
+		fatal("err:", n, "records drift from the API", api.Info.Title)
+	}
+}
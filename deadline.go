@@ -0,0 +1,53 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Exit status when -deadline is exceeded, as for timeout(1)
+const exitTimeout = 124
+
+var (
+	stageMu sync.Mutex
+	stage   = "starting"
+	partial string // Temporary output file being written, removed if -deadline is exceeded
+)
+
+// Record how far the run has got, for reporting if -deadline is exceeded
+func progress(s ...interface{}) {
+	stageMu.Lock()
+	defer stageMu.Unlock()
+
+	stage = fmt.Sprint(s...)
+}
+
+// Record the temporary output file being written, if any
+func scratch(path string) {
+	stageMu.Lock()
+	defer stageMu.Unlock()
+
+	partial = path
+}
+
+// Start the -deadline for the whole run, if any
+// Once it expires, the run is abandoned wherever it is, leaving any -o file as it was
+func startDeadline() {
+	if *deadline <= 0 {
+		return
+	}
+
+	time.AfterFunc(*deadline, func() {
+		stageMu.Lock()
+		logLine("error", "err: -deadline of", *deadline, "exceeded while", stage)
+		if len(partial) > 0 {
+			os.Remove(partial)
+		}
+		os.Exit(exitTimeout)
+	})
+}
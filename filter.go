@@ -0,0 +1,82 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"strings"
+)
+
+// filter is a comma separated list flag, counting how often each value matches
+type filter struct {
+	flag   string         // Name of the flag, for reporting
+	values []string       // Values in the order given
+	hits   map[string]int // Matches per value
+}
+
+// Filters of parameters to emit, set up by mk
+var only, exclude, tagged *filter
+
+// Parse the filter flags
+func setupFilters() {
+	only = newFilter("only", *onlyNames)
+	exclude = newFilter("exclude", *omitNames)
+	tagged = newFilter("tag", *tags)
+}
+
+// Build a filter from a comma separated list, empty values are ignored
+func newFilter(flag, list string) *filter {
+	f := &filter{flag: flag, hits: make(map[string]int)}
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			f.values = append(f.values, v)
+		}
+	}
+
+	return f
+}
+
+// Whether the filter has any values to match
+func (f *filter) active() bool {
+	return f != nil && len(f.values) > 0
+}
+
+// Whether any candidate is one of the filter's values, counting each match
+func (f *filter) match(candidates ...string) bool {
+	if !f.active() {
+		return false
+	}
+
+	matched := false
+	for _, v := range f.values {
+		for _, c := range candidates {
+			if c == v {
+				f.hits[v]++
+				matched = true
+			}
+		}
+	}
+
+	return matched
+}
+
+// Whether a parameter of an operation passes the -only, -exclude, and -tag filters
+// Every filter is matched, so each counts its matches independently of the others
+func filtered(u use) bool {
+	tag := tagged.match(u.operation.Tags...)
+	name := only.match(u.parameter.Name)
+	omit := exclude.match(u.parameter.Name)
+
+	return (tag || !tagged.active()) && (name || !only.active()) && !omit
+}
+
+// Warn of filter values which matched nothing, usually a typo
+func reportFilters() {
+	for _, f := range []*filter{only, exclude, tagged} {
+		for _, v := range f.values {
+			if f.hits[v] < 1 {
+				warn("warn: -"+f.flag, "value", v, "matched nothing")
+			}
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/seh-msft/openapi"
+	"gopkg.in/yaml.v3"
+)
+
+// Specification encodings understood by f2api
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+// Parse an OpenAPI specification, sniffing its encoding and version from content
+func parseAPI(path string, data []byte) (spec, error) {
+	var api spec
+
+	doc, err := decode(data, *specFormat)
+	if err != nil {
+		return api, err
+	}
+
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return api, errors.New("specification is not an object")
+	}
+
+	// JSON objects keep the last of duplicate keys silently, YAML rejects them
+	if sniffFormat(data) == formatJSON {
+		for _, dup := range duplicatePaths(data) {
+			warn("warn: path", dup.path, "is defined", dup.n, "times in", path, "using the last definition")
+		}
+	}
+
+	// Decide the translation path from the version field
+	if v, ok := obj["swagger"]; ok {
+		version := fmt.Sprint(v)
+		if !strings.HasPrefix(version, "2.") {
+			return api, errors.New("unsupported swagger version " + version)
+		}
+		fromSwagger(obj)
+
+	} else if v, ok := obj["openapi"]; ok {
+		version := fmt.Sprint(v)
+		if !strings.HasPrefix(version, "3.") {
+			return api, errors.New("unsupported openapi version " + version)
+		}
+	}
+
+	// Relative external references are relative to -base-dir, or the spec itself
+	base := *baseDir
+	if len(base) < 1 {
+		base = filepath.Dir(path)
+	}
+	rebase(obj, base, "")
+
+	if err := resolveRefs(obj); err != nil {
+		return api, err
+	}
+
+	// openapi.API only models component schemas, other sections fail to decode
+	typed := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		typed[k] = v
+	}
+	if components, ok := obj["components"].(map[string]interface{}); ok {
+		typed["components"] = map[string]interface{}{"schemas": components["schemas"]}
+	}
+
+	buf, err := json.Marshal(typed)
+	if err != nil {
+		return api, err
+	}
+
+	api.doc = obj
+	api.API, err = openapi.Parse(bytes.NewReader(buf))
+	return api, err
+}
+
+// Parse a standalone JSON Schema under -schema, as an API without paths
+// Its title, if any, is the title of the API
+func parseSchema(path string, data []byte) (spec, error) {
+	var api spec
+
+	doc, err := decode(data, *specFormat)
+	if err != nil {
+		return api, err
+	}
+
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return api, errors.New("schema is not an object")
+	}
+
+	base := *baseDir
+	if len(base) < 1 {
+		base = filepath.Dir(path)
+	}
+	rebase(obj, base, "")
+
+	api.doc = obj
+	api.root = obj
+	api.Info.Title, _ = obj["title"].(string)
+	return api, nil
+}
+
+// duplicate is a key of an object defined more than once
+type duplicate struct {
+	path string
+	n    int
+}
+
+// Keys of the top-level paths object of a JSON document defined more than once, in order
+func duplicatePaths(data []byte) []duplicate {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		if t != "paths" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+			return nil
+		}
+
+		var order []string
+		counts := make(map[string]int)
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return nil
+			}
+			key, _ := t.(string)
+			if counts[key] == 1 {
+				order = append(order, key)
+			}
+			counts[key]++
+
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil
+			}
+		}
+
+		var dups []duplicate
+		for _, key := range order {
+			dups = append(dups, duplicate{key, counts[key]})
+		}
+		return dups
+	}
+
+	return nil
+}
+
+// Decode a JSON or YAML document, sniffing which it is from content
+// The fallback format, usually the -format flag, is only consulted if the content is ambiguous
+func decode(data []byte, fallback string) (interface{}, error) {
+	format := sniffFormat(data)
+	if format == "" {
+		format = strings.ToLower(fallback)
+	}
+
+	var doc interface{}
+	switch format {
+	case formatJSON:
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+
+	case formatYAML:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		doc = normalize(doc)
+
+	case "":
+		return nil, errors.New("could not detect specification format, use -format")
+
+	default:
+		return nil, errors.New("unknown specification format " + format)
+	}
+
+	return doc, nil
+}
+
+// Guess the encoding of a specification from its first meaningful bytes
+// Returns an empty string if the content is ambiguous
+func sniffFormat(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimLeftFunc(data, unicode.IsSpace)
+	if len(trimmed) < 1 {
+		return ""
+	}
+
+	if trimmed[0] == '{' {
+		return formatJSON
+	}
+
+	// YAML has no single opening byte, look for a document marker or version key
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for sc.Scan() {
+		line := strings.TrimRightFunc(sc.Text(), unicode.IsSpace)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+
+		case line == "---" || strings.HasPrefix(line, "%YAML"):
+			return formatYAML
+		}
+
+		key := strings.Trim(strings.SplitN(line, ":", 2)[0], `"'`)
+		if strings.Contains(line, ":") && (key == "openapi" || key == "swagger") {
+			return formatYAML
+		}
+	}
+
+	return ""
+}
+
+// Convert decoded YAML into values encoding/json can marshal
+// YAML permits non-string map keys such as bare response codes
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+		return v
+
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[fmt.Sprint(k)] = normalize(e)
+		}
+		return out
+
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+		return v
+	}
+
+	return v
+}
+
+// Translate the parts of a swagger 2.0 document we use into their OpenAPI 3 form
+// Swagger 2.0 parameters describe their type inline rather than under "schema"
+// A basePath becomes the path of a server
+func fromSwagger(obj map[string]interface{}) {
+	if base, ok := obj["basePath"].(string); ok {
+		if _, ok := obj["servers"]; !ok {
+			obj["servers"] = []interface{}{map[string]interface{}{"url": base}}
+		}
+	}
+
+	paths, _ := obj["paths"].(map[string]interface{})
+	for _, p := range paths {
+		methods, _ := p.(map[string]interface{})
+		for _, m := range methods {
+			method, _ := m.(map[string]interface{})
+			parameters, _ := method["parameters"].([]interface{})
+			for _, p := range parameters {
+				parameter, ok := p.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if _, ok := parameter["schema"]; ok {
+					continue
+				}
+
+				schema := make(map[string]interface{})
+				for _, key := range []string{"type", "items", "enum", "default", "minItems", "maxItems"} {
+					if v, ok := parameter[key]; ok {
+						schema[key] = v
+						delete(parameter, key)
+					}
+				}
+				parameter["schema"] = schema
+			}
+		}
+	}
+}
@@ -5,4 +5,6 @@ go 1.16
 require (
 	github.com/seh-msft/cfg v0.0.0-20210114223223-348cc89c9d0c
 	github.com/seh-msft/openapi v0.0.0-20210616183003-5b1ff0059ea1
+	golang.org/x/text v0.3.7
+	gopkg.in/yaml.v3 v3.0.1
 )
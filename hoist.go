@@ -0,0 +1,83 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/seh-msft/cfg"
+)
+
+// block is a set of constraint lines and the records which have exactly those lines
+type block struct {
+	lines   []string
+	records []string
+}
+
+// Report the constraint blocks repeated across the records of a cfg, for -hoist-constraints
+// cfg(2) has no construct for records to share or inherit tuples, so nothing is hoisted
+func hoist(data []byte, out io.Writer) {
+	c, err := cfg.Load(bytes.NewReader(data))
+	if err != nil {
+		fatal("err: could not cfg parse file →", err)
+	}
+
+	var (
+		order     []*block
+		lines     int
+		repeated  int
+		withBlock int
+	)
+	blocks := make(map[string]*block)
+	for _, r := range c.Records {
+		if len(r.Tuples) < 2 {
+			continue
+		}
+		withBlock++
+
+		var text []string
+		for _, t := range r.Tuples[1:] {
+			text = append(text, tupleText(t, false))
+		}
+		lines += len(text)
+
+		key := strings.Join(text, "\n")
+		b, ok := blocks[key]
+		if ok {
+			repeated += len(text)
+		} else {
+			b = &block{lines: text}
+			blocks[key] = b
+			order = append(order, b)
+		}
+		b.records = append(b.records, r.PrimaryKey())
+	}
+
+	// Most shared first, otherwise as first seen
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(order[i].records) > len(order[j].records)
+	})
+
+	fmt.Fprintf(out, "# %d records, %d with constraints, %d distinct constraint blocks\n", len(c.Records), withBlock, len(order))
+	for _, b := range order {
+		if len(b.records) < 2 {
+			continue
+		}
+
+		fmt.Fprintf(out, "# shared by %d of %d records, first %s:\n", len(b.records), withBlock, b.records[0])
+		for _, line := range b.lines {
+			fmt.Fprintf(out, "#\t%s\n", line)
+		}
+	}
+
+	ratio := 0.0
+	if lines > 0 {
+		ratio = float64(repeated) / float64(lines)
+	}
+	fmt.Fprintf(out, "# duplication ratio %.2f, %d of %d constraint lines repeat an earlier block\n", ratio, repeated, lines)
+}
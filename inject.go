@@ -0,0 +1,76 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/seh-msft/cfg"
+)
+
+// Lines delimiting the generated block of an -inject file
+const (
+	beginMarker = "# BEGIN GENERATED"
+	endMarker   = "# END GENERATED"
+)
+
+// Replace the generated block of a hand-maintained cfg, leaving the rest untouched
+// If the file has no markers, they are appended along with the block
+func inject(path, generated string) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		fatal("err: could not read inject file →", err)
+	}
+	if err := checkUTF8(path, data); err != nil {
+		fatal("err:", err)
+	}
+	content := string(data)
+
+	block := beginMarker + "\n" + strings.TrimRight(generated, "\n") + "\n" + endMarker + "\n"
+
+	lines := strings.SplitAfter(content, "\n")
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case beginMarker:
+			if begin < 0 {
+				begin = i
+			}
+		case endMarker:
+			if begin >= 0 && end < 0 {
+				end = i
+			}
+		}
+	}
+
+	switch {
+	case begin < 0:
+		if len(content) > 0 && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if len(content) > 0 {
+			content += "\n"
+		}
+		content += block
+
+	case end < 0:
+		fatal("err: inject file has", beginMarker, "but no", endMarker)
+
+	default:
+		content = strings.Join(lines[:begin], "") + block + strings.Join(lines[end+1:], "")
+	}
+
+	if _, err := cfg.Load(strings.NewReader(content)); err != nil {
+		fatal("err: injected cfg is invalid, leaving the file untouched →", err)
+	}
+
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode()
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		fatal("err: could not write inject file →", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Where log lines are written, stderr unless -log-file is set
+var logOut io.Writer = os.Stderr
+
+// Open the -log-file, if any, and check the -log-format
+func openLog() {
+	switch *logFormat {
+	case "text", "json":
+	default:
+		fatal("err: unknown -log-format, must be text or json →", *logFormat)
+	}
+
+	if len(*logFile) < 1 {
+		return
+	}
+
+	f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fatal("err: could not open log file →", err)
+	}
+	logOut = f
+}
+
+// Write one log line at a level in the -log-format
+// Text lines are written as-is, to keep plain stderr output unchanged
+func logLine(level string, s ...interface{}) {
+	msg := strings.TrimSuffix(fmt.Sprintln(s...), "\n")
+
+	if *logFormat != "json" {
+		fmt.Fprintln(logOut, msg)
+		return
+	}
+
+	line, _ := json.Marshal(struct {
+		Time  string `json:"time"`
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+	}{time.Now().UTC().Format(time.RFC3339), level, msg})
+	fmt.Fprintf(logOut, "%s\n", line)
+}
+
+var (
+	// Warnings already logged, as several passes may walk the same parameters
+	warned = make(map[string]bool)
+
+	// Number of warnings logged
+	warnings int
+)
+
+// Warn - log a warning once and continue, or end the program under -warnings-as-errors
+func warn(s ...interface{}) {
+	if *wError {
+		fatal(s...)
+	}
+
+	msg := fmt.Sprintln(s...)
+	if warned[msg] {
+		return
+	}
+	warned[msg] = true
+	warnings++
+
+	logLine("warn", s...)
+}
+
+// Info - log an informational message if -verbose is set
+func info(s ...interface{}) {
+	if *verbose {
+		logLine("info", s...)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// row is one identifier of a Markdown table
+type row struct {
+	name     string
+	value    string
+	required bool
+	kind     string
+	sources  []string
+}
+
+// Emit a Markdown table of the identifiers of each API, under a heading per API
+// As in loose mode, there is one row per identifier, listing every endpoint using it
+func markdown(apis []spec, out io.Writer) {
+	for _, api := range apis {
+		var names []string
+		rows := make(map[string]*row)
+		walk(api, func(u use) {
+			name := clean(identifier(u))
+			r, ok := rows[name]
+			if !ok {
+				v, sampled := value(api, u)
+				if sampled {
+					v += " (sample)"
+				}
+
+				r = &row{name: name, value: v, kind: schemaType(u)}
+				rows[name] = r
+				names = append(names, name)
+			}
+
+			r.required = r.required || u.parameter.Required
+			r.sources = append(r.sources, fmt.Sprintf("%s %s (%s)", strings.ToUpper(u.method), u.path, u.parameter.In))
+		})
+
+		fmt.Fprintf(out, "## %s\n\n", cell(api.Info.Title))
+		fmt.Fprintf(out, "| Identifier | Value | Required | Type | Source |\n")
+		fmt.Fprintf(out, "| --- | --- | --- | --- | --- |\n")
+
+		for _, name := range names {
+			r := rows[name]
+			required := "no"
+			if r.required {
+				required = "yes"
+			}
+
+			fmt.Fprintf(out, "| %s | %s | %s | %s | %s |\n", cell(r.name), cell(r.value), required, cell(r.kind), cell(strings.Join(r.sources, ", ")))
+		}
+
+		fmt.Fprintf(out, "\n")
+	}
+}
+
+// Escape text for a Markdown table cell
+func cell(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
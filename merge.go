@@ -0,0 +1,268 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/seh-msft/cfg"
+)
+
+// jsonRecord is the structured JSON form of a cfg record
+type jsonRecord struct {
+	Key    string       `json:"key"`    // Primary key of the record
+	Tuples [][]jsonAttr `json:"tuples"` // Every tuple, the first holding the key
+}
+
+// jsonAttr is the structured JSON form of a cfg attribute
+type jsonAttr struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// Strategies for combining the tuples of records sharing a key, for -merge-strategy
+const (
+	strategyConcat = "concat"
+	strategyUnion  = "union"
+	strategyDeep   = "deep"
+)
+
+// Check the -merge-strategy is known
+func checkStrategy() {
+	switch *strategy {
+	case strategyConcat, strategyUnion, strategyDeep:
+	default:
+		fatal("err: unknown -merge-strategy, must be concat, union, or deep →", *strategy)
+	}
+}
+
+// Load and merge every cfg file given to -merge, from -cfg and the arguments
+func mergeInputs(args []string) cfg.Cfg {
+	paths := args
+	if len(*cfgFile) > 0 {
+		paths = append([]string{*cfgFile}, args...)
+	}
+	if len(paths) < 1 {
+		fatal("err: -merge requires -cfg or one or more argument files")
+	}
+
+	var cs []cfg.Cfg
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fatal("err: could not open file →", err)
+		}
+		if err := checkUTF8(path, data); err != nil {
+			fatal("err:", err)
+		}
+
+		c, err := cfg.Load(bytes.NewReader(data))
+		if err != nil {
+			fatal("err: could not cfg parse file", path, "→", err)
+		}
+		cs = append(cs, c)
+	}
+
+	return merge(cs)
+}
+
+// Merge cfgs into one, combining records which share a primary key
+// The first record with a key keeps its place and first tuple, and later records add their other tuples by the -merge-strategy
+func merge(cs []cfg.Cfg) cfg.Cfg {
+	var out cfg.Cfg
+	index := make(map[string]*cfg.Record)
+
+	for _, c := range cs {
+		for _, r := range c.Records {
+			key := r.PrimaryKey()
+			if have, ok := index[key]; ok {
+				have.Tuples = combine(have.Tuples, r.Tuples[1:])
+				continue
+			}
+
+			copied := &cfg.Record{Tuples: append(cfg.Tuples{}, r.Tuples...)}
+			index[key] = copied
+			out.Records = append(out.Records, copied)
+		}
+	}
+
+	if *strategy == strategyDeep {
+		for _, r := range out.Records {
+			canonical(r)
+		}
+	}
+
+	out.BuildMap()
+	return out
+}
+
+// Add the constraint tuples of a record to those of one with the same key
+// Under concat they are appended, otherwise only those not already present are
+func combine(have, more cfg.Tuples) cfg.Tuples {
+	if *strategy == strategyConcat {
+		return append(have, more...)
+	}
+
+	present := make(map[string]bool)
+	for _, t := range have[1:] {
+		present[tupleText(t, false)] = true
+	}
+
+	for _, t := range more {
+		if text := tupleText(t, false); !present[text] {
+			present[text] = true
+			have = append(have, t)
+		}
+	}
+
+	return have
+}
+
+// Order the constraints of a record canonically, for the deep strategy
+// The key tuple stays first, then disallows, permits, and other tuples, each sorted by text
+// A disallow whose patterns are all trivial matches everything, and subsumes any other disallow
+func canonical(r *cfg.Record) {
+	var disallows, permits, others cfg.Tuples
+	seen := make(map[string]bool)
+	everything := false
+	for _, t := range r.Tuples[1:] {
+		text := tupleText(t, false)
+		if seen[text] {
+			continue
+		}
+		seen[text] = true
+
+		switch t.Attributes[0].Name {
+		case "disallow":
+			disallows = append(disallows, t)
+			everything = everything || catchAll(t)
+
+		case "permit":
+			permits = append(permits, t)
+
+		default:
+			others = append(others, t)
+		}
+	}
+
+	if everything {
+		var kept cfg.Tuples
+		for _, t := range disallows {
+			if catchAll(t) {
+				kept = append(kept, t)
+			}
+		}
+		disallows = kept
+	}
+
+	tuples := cfg.Tuples{r.Tuples[0]}
+	for _, group := range []cfg.Tuples{disallows, permits, others} {
+		sort.SliceStable(group, func(i, j int) bool {
+			return tupleText(group[i], false) < tupleText(group[j], false)
+		})
+		tuples = append(tuples, group...)
+	}
+	r.Tuples = tuples
+}
+
+// Whether every pattern of a constraint tuple is trivial, so it matches everything
+func catchAll(t *cfg.Tuple) bool {
+	for _, a := range t.Attributes[1:] {
+		if !trivial(a.Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Write a cfg as text, quoting as generated output is
+func format(c cfg.Cfg, out io.Writer) {
+	for _, r := range c.Records {
+		for i, t := range r.Tuples {
+			if i > 0 {
+				fmt.Fprintf(out, "\t")
+			}
+			fmt.Fprintf(out, "%s\n", tupleText(t, i == 0))
+		}
+		fmt.Fprintf(out, "\n")
+	}
+}
+
+// Text of a tuple, quoting as generated output is
+// Attributes other than a record's key are written without '=' when valueless
+func tupleText(t *cfg.Tuple, key bool) string {
+	var sb strings.Builder
+	for j, a := range t.Attributes {
+		if j > 0 {
+			sb.WriteString(" ")
+		}
+
+		sb.WriteString(clean(a.Name))
+		if len(a.Value) > 0 {
+			sb.WriteString("=" + clean(a.Value))
+		} else if key && j == 0 {
+			sb.WriteString("=")
+		}
+	}
+
+	return sb.String()
+}
+
+// jsonMeta describes the records of structured JSON under -json-root-key
+type jsonMeta struct {
+	Title   string `json:"title,omitempty"`   // Of the -api, if given
+	Version string `json:"version,omitempty"` // Of the -api, if given
+	Records int    `json:"records"`
+	Tuples  int    `json:"tuples"`
+}
+
+// Encode a cfg as structured JSON, an array of records
+// Under -json-root-key, the array is wrapped in an object with a "meta" object
+func structured(c cfg.Cfg, out io.Writer) {
+	records := []jsonRecord{}
+	tuples := 0
+	for _, r := range c.Records {
+		jr := jsonRecord{Key: r.PrimaryKey()}
+		for _, t := range r.Tuples {
+			attrs := []jsonAttr{}
+			for _, a := range t.Attributes {
+				attrs = append(attrs, jsonAttr{a.Name, a.Value})
+			}
+			jr.Tuples = append(jr.Tuples, attrs)
+		}
+		tuples += len(jr.Tuples)
+		records = append(records, jr)
+	}
+
+	var v interface{} = records
+	if len(*rootKey) > 0 {
+		if *rootKey == "meta" {
+			fatal("err: -json-root-key must not be meta")
+		}
+
+		meta := jsonMeta{Records: len(records), Tuples: tuples}
+		if len(*apiFile) > 0 {
+			api, err := f2api(*apiFile)
+			if err != nil {
+				fatal("err:", err)
+			}
+			meta.Title, meta.Version = api.Info.Title, api.Info.Version
+		}
+
+		v = map[string]interface{}{*rootKey: records, "meta": meta}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(v); err != nil {
+		fatal("err: could not encode to JSON →", err)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Separates an operation from a parameter name under -key-by-operation
+const operationSep = "."
+
+// Identifier for a parameter's record, before cleaning
+func identifier(u use) string {
+	name := u.parameter.Name
+	if *unicodeNFC {
+		name = normalizeName(u, name)
+	}
+
+	if *opKeys {
+		// Operations without an operationId are named by method and path
+		prefix := u.operation.OperationID
+		if prefix == "" {
+			prefix = u.method + "_" + u.path
+		}
+		name = prefix + operationSep + name
+	}
+
+	if u.parameter.Schema.Type == "array" {
+		name += *arraySufx
+	}
+
+	if old, _ := u.raw["deprecated"].(bool); old {
+		name += *deprecated
+	}
+
+	if len(*namespace) > 0 {
+		name = *namespace + operationSep + name
+	}
+
+	return name
+}
+
+// Check each dotted component of the -namespace is a valid bare name
+func checkNamespace() {
+	if len(*namespace) < 1 {
+		return
+	}
+
+	for _, part := range strings.Split(*namespace, operationSep) {
+		if part == "" || !bare(part) || commented(part) {
+			fatal("err: -namespace components must be non-empty and not need quoting →", *namespace)
+		}
+	}
+}
+
+// Parameter names already reported as normalized, as identifier is called on each pass over the parameters
+var normalized = make(map[string]bool)
+
+// Apply NFC to a parameter name and strip control and zero-width characters
+func normalizeName(u use, name string) string {
+	out := strings.Map(func(r rune) rune {
+		if unicode.In(r, unicode.Cc, unicode.Cf) {
+			return -1
+		}
+		return r
+	}, norm.NFC.String(name))
+
+	if key := u.path + "\x00" + name; out != name && !normalized[key] {
+		normalized[key] = true
+		info("info: normalized parameter name", strconv.QuoteToASCII(name), "of", u.path, "to", strconv.QuoteToASCII(out))
+	}
+
+	return out
+}
+
+// Comment lines to emit above a parameter's record
+func comments(u use) []string {
+	var out []string
+
+	if kind := schemaType(u); *types && kind != "" {
+		out = append(out, "type: "+kind)
+	}
+
+	if *types && u.schema != "" {
+		out = append(out, "from schema: "+u.schema)
+	}
+
+	if len(u.alternatives) > 1 {
+		out = append(out, "ambiguous: in alternatives "+strings.Join(u.alternatives, ", "))
+	}
+
+	return out
+}
+
+// Description of a parameter's schema type, including the item type of arrays
+func schemaType(u use) string {
+	schema := u.parameter.Schema
+	if schema.Type == "array" && schema.Items.Type != "" {
+		return "array of " + schema.Items.Type
+	}
+
+	return schema.Type
+}
+
+// Name of a parameter's operation, its operationId or else its method and path
+func operation(u use) string {
+	if len(u.operation.OperationID) > 0 {
+		return u.operation.OperationID
+	}
+
+	return strings.ToUpper(u.method) + " " + u.path
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/seh-msft/cfg"
+)
+
+// Emit the -only-constraints view of a record, its constraints under a comment naming it
+// Records without constraints have no policy to review and are omitted
+func policy(out io.Writer, name string, constraints []string) {
+	if len(constraints) < 1 {
+		return
+	}
+
+	fmt.Fprintf(out, "# %s\n", name)
+	for _, constraint := range constraints {
+		fmt.Fprintf(out, "\t%s\n", constraint)
+	}
+
+	fmt.Fprintf(out, "\n")
+}
+
+// Emit the -only-constraints view of a loaded cfg
+// Constraint lines are the indented tuples of each record, kept as written
+func loadedPolicy(data []byte, out io.Writer) {
+	if _, err := cfg.Load(strings.NewReader(string(data))); err != nil {
+		fatal("err: could not cfg parse file →", err)
+	}
+
+	var (
+		name        string
+		constraints []string
+	)
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.IndexRune(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.TrimLeft(line, " \t") != line {
+			constraints = append(constraints, strings.TrimSpace(line))
+			continue
+		}
+
+		// An unindented line starts a new record
+		policy(out, name, constraints)
+		c, _ := cfg.Load(strings.NewReader(line + "\n"))
+		name, constraints = c.Records[0].PrimaryKey(), nil
+	}
+
+	policy(out, name, constraints)
+}
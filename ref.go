@@ -0,0 +1,213 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Replace $ref parameters and parameter schemas with what they reference
+func resolveRefs(doc map[string]interface{}) error {
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, p := range paths {
+		methods, _ := p.(map[string]interface{})
+		for _, m := range methods {
+			method, _ := m.(map[string]interface{})
+			parameters, _ := method["parameters"].([]interface{})
+			for i, p := range parameters {
+				p, err := deref(doc, p)
+				if err != nil {
+					return err
+				}
+				parameters[i] = p
+
+				parameter, ok := p.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if schema, ok := parameter["schema"]; ok {
+					parameter["schema"], err = deref(doc, schema)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Follow a chain of $ref values to the object it ends at
+// Chains longer than -ref-depth or which revisit a reference are errors
+func deref(doc map[string]interface{}, v interface{}) (interface{}, error) {
+	v, _, err := derefAt(doc, v, "")
+	return v, err
+}
+
+// Follow a chain of $ref values from the JSON Pointer at, as deref
+// The pointer returned is to the object the chain ends at, qualified by its file if not in doc
+func derefAt(doc map[string]interface{}, v interface{}, at string) (interface{}, string, error) {
+	seen := make(map[string]bool)
+
+	for depth := 0; ; depth++ {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return v, at, nil
+		}
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return v, at, nil
+		}
+
+		if depth >= *refDepth {
+			return nil, "", fmt.Errorf("$ref chain exceeds -ref-depth %d at %s", *refDepth, ref)
+		}
+		if seen[ref] {
+			return nil, "", errors.New("$ref cycle at " + ref)
+		}
+		seen[ref] = true
+
+		var err error
+		v, err = pointer(doc, ref)
+		if err != nil {
+			return nil, "", err
+		}
+		at = strings.TrimPrefix(ref, "#")
+	}
+}
+
+// Look up a JSON pointer reference such as "#/components/parameters/limit"
+// References into other files, such as "common.yaml#/parameters/limit", load that file
+func pointer(doc map[string]interface{}, ref string) (interface{}, error) {
+	file, fragment := ref, ""
+	if i := strings.IndexByte(ref, '#'); i >= 0 {
+		file, fragment = ref[:i], ref[i+1:]
+	}
+
+	if len(file) > 0 {
+		if strings.Contains(file, "://") {
+			return nil, errors.New("unsupported remote $ref " + ref)
+		}
+
+		var err error
+		doc, err = external(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var v interface{} = doc
+	for _, token := range strings.Split(fragment, "/")[1:] {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[token]
+			if !ok {
+				return nil, errors.New("unresolved $ref " + ref)
+			}
+			v = next
+
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, errors.New("unresolved $ref " + ref)
+			}
+			v = node[i]
+
+		default:
+			return nil, errors.New("unresolved $ref " + ref)
+		}
+	}
+
+	return v, nil
+}
+
+// External documents loaded for $ref, by path
+var externals = make(map[string]map[string]interface{})
+
+// Load a document referenced by $ref, with its own references made relative to it
+func external(path string) (map[string]interface{}, error) {
+	if doc, ok := externals[path]; ok {
+		return doc, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load $ref file %s → %v", path, err)
+	}
+	if err := checkUTF8(path, data); err != nil {
+		return nil, err
+	}
+
+	v, err := decode(data, extFormat(path))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse $ref file %s → %v", path, err)
+	}
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("$ref file is not an object " + path)
+	}
+
+	rebase(doc, filepath.Dir(path), path)
+	externals[path] = doc
+
+	return doc, nil
+}
+
+// Format of a $ref file by its extension, else the -format flag
+// Fragments rarely have the document marker or version key which sniffing looks for
+func extFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".json":
+		return formatJSON
+	}
+
+	return *specFormat
+}
+
+// Rewrite the $refs of a document so they can be followed from any other
+// References to other files are joined to dir, and local references are qualified by file, if any
+func rebase(v interface{}, dir, file string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			v["$ref"] = absolute(ref, dir, file)
+		}
+		for _, e := range v {
+			rebase(e, dir, file)
+		}
+
+	case []interface{}:
+		for _, e := range v {
+			rebase(e, dir, file)
+		}
+	}
+}
+
+// Qualify one $ref appearing in a document of the directory dir
+func absolute(ref, dir, file string) string {
+	target, fragment := ref, ""
+	if i := strings.IndexByte(ref, '#'); i >= 0 {
+		target, fragment = ref[:i], ref[i:]
+	}
+
+	switch {
+	case target == "":
+		return file + fragment
+
+	case strings.Contains(target, "://") || filepath.IsAbs(target):
+		return ref
+	}
+
+	return filepath.Join(dir, target) + fragment
+}
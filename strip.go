@@ -0,0 +1,58 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/seh-msft/cfg"
+)
+
+// Re-emit a cfg without comments, leaving records and constraints as written
+// Like cfg.Load, a comment runs from any '#' to the end of its line
+func stripComments(data []byte, out io.Writer) {
+	before, err := cfg.Load(bytes.NewReader(data))
+	if err != nil {
+		fatal("err: could not cfg parse file →", err)
+	}
+
+	var b strings.Builder
+	blank := true
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if i := strings.IndexRune(line, '#'); i >= 0 {
+			line = strings.TrimRight(line[:i], " \t") + "\n"
+			if strings.TrimSpace(line) == "" {
+				// Comment lines are dropped entirely
+				continue
+			}
+		}
+
+		// Runs of blank lines left behind collapse to one
+		if strings.TrimSpace(line) == "" {
+			if blank {
+				continue
+			}
+			blank = true
+			b.WriteString("\n")
+			continue
+		}
+
+		blank = false
+		b.WriteString(line)
+	}
+	stripped := strings.TrimRight(b.String(), "\n") + "\n"
+
+	// Records must survive the round trip unchanged
+	after, err := cfg.Load(strings.NewReader(stripped))
+	var want, got strings.Builder
+	emit(before, cfg.Double, &want)
+	emit(after, cfg.Double, &got)
+	if err != nil || got.String() != want.String() {
+		fatal("err: stripping comments changed the cfg, refusing to emit it")
+	}
+
+	io.WriteString(out, stripped)
+}
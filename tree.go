@@ -0,0 +1,99 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Branches of a -tree, drawn with box-drawing characters or plain indentation
+type branches struct {
+	mid, last, pipe, space string
+}
+
+var (
+	boxBranches   = branches{"├── ", "└── ", "│   ", "    "}
+	plainBranches = branches{"    ", "    ", "    ", "    "}
+)
+
+// Emit a tree of each API's paths, methods, and parameters, for discovery rather than as a cfg
+// Box-drawing characters are used only when writing to a terminal
+func tree(apis []spec, out io.Writer) {
+	b := plainBranches
+	if *outFile == "" && terminal(os.Stdout) {
+		b = boxBranches
+	}
+
+	for _, api := range apis {
+		params := make(map[string][]use)
+		survey(api, func(u use) {
+			key := u.path + " " + u.method
+			params[key] = append(params[key], u)
+		})
+
+		var paths []string
+		for path := range api.Paths {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		fmt.Fprintln(out, api.Info.Title)
+		for i, path := range paths {
+			pathLast := i == len(paths)-1
+			fmt.Fprintln(out, b.branch(pathLast)+path)
+
+			var methods []string
+			for method := range api.Paths[path] {
+				methods = append(methods, method)
+			}
+			sort.Strings(methods)
+
+			indent := b.indent(pathLast)
+			for j, method := range methods {
+				methodLast := j == len(methods)-1
+				fmt.Fprintln(out, indent+b.branch(methodLast)+strings.ToUpper(method))
+
+				uses := params[path+" "+method]
+				for k, u := range uses {
+					required := "optional"
+					if u.parameter.Required {
+						required = "required"
+					}
+
+					fmt.Fprintf(out, "%s%s%s%s (%s, %s)\n", indent, b.indent(methodLast), b.branch(k == len(uses)-1), u.parameter.Name, u.parameter.In, required)
+				}
+			}
+		}
+
+		fmt.Fprintln(out)
+	}
+}
+
+// Branch leading to a node, which may be the last of its siblings
+func (b branches) branch(last bool) string {
+	if last {
+		return b.last
+	}
+
+	return b.mid
+}
+
+// Indentation under a node, continuing its parent's branch unless it was the last
+func (b branches) indent(last bool) string {
+	if last {
+		return b.space
+	}
+
+	return b.pipe
+}
+
+// Whether a file is a terminal
+func terminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/seh-msft/cfg"
+)
+
+// Merge freshly generated identifiers into an existing cfg
+// Under the concat -merge-strategy, existing records are kept verbatim, even if stale, and new records are appended
+func update(existing []byte, generated string, out io.Writer) {
+	old, err := cfg.Load(strings.NewReader(string(existing)))
+	if err != nil {
+		fatal("err: could not cfg parse existing output file →", err)
+	}
+
+	// Other strategies combine records sharing a name, so the cfg is rewritten
+	if *strategy != strategyConcat {
+		fresh, err := cfg.Load(strings.NewReader(generated))
+		if err != nil {
+			fatal("err: could not cfg parse generated output →", err)
+		}
+		format(merge([]cfg.Cfg{old, fresh}), out)
+		changelog(byName(old, fresh))
+		return
+	}
+
+	have := make(map[string]bool)
+	for _, r := range old.Records {
+		have[recordKey(r)] = true
+	}
+
+	var (
+		added     []string
+		unchanged int
+		seen      = make(map[string]bool)
+		appended  strings.Builder
+		header    string
+	)
+
+	// Each paragraph of generated output is an API header or a record
+	for _, para := range strings.Split(generated, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+
+		c, err := cfg.Load(strings.NewReader(para + "\n"))
+		if err != nil {
+			fatal("err: could not cfg parse generated output →", err)
+		}
+		if len(c.Records) < 1 {
+			header = para
+			continue
+		}
+
+		fresh := false
+		for _, r := range c.Records {
+			key := recordKey(r)
+			switch {
+			case seen[key]:
+				continue
+			case have[key]:
+				unchanged++
+			default:
+				added = append(added, r.PrimaryKey())
+				fresh = true
+			}
+			seen[key] = true
+		}
+
+		if !fresh {
+			continue
+		}
+		if header != "" {
+			appended.WriteString(header + "\n\n")
+			header = ""
+		}
+		appended.WriteString(para + "\n\n")
+	}
+
+	stale := 0
+	for key := range have {
+		if !seen[key] {
+			stale++
+		}
+	}
+
+	content := string(existing)
+	if appended.Len() > 0 && len(content) > 0 {
+		for !strings.HasSuffix(content, "\n\n") {
+			content += "\n"
+		}
+	}
+	io.WriteString(out, content+appended.String())
+
+	changelog(added, stale, unchanged)
+}
+
+// Write the -update changelog, if requested
+// Each line is prefixed by its kind to keep the format greppable
+func changelog(added []string, stale, unchanged int) {
+	if *changeFile == "" {
+		return
+	}
+
+	var w io.Writer = os.Stderr
+	if *changeFile != "-" {
+		f, err := os.Create(*changeFile)
+		if err != nil {
+			fatal("err: could not open changelog file →", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	for _, name := range added {
+		fmt.Fprintf(w, "added: %s\n", name)
+	}
+	fmt.Fprintf(w, "summary: %d added, %d stale, %d unchanged\n", len(added), stale, unchanged)
+}
+
+// Changes between an existing cfg and generated records, by record name
+// Merging strategies other than concat combine records of the same name, whatever they permit
+func byName(old, fresh cfg.Cfg) (added []string, stale, unchanged int) {
+	have := make(map[string]bool)
+	for _, r := range old.Records {
+		have[r.PrimaryKey()] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, r := range fresh.Records {
+		name := r.PrimaryKey()
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if have[name] {
+			unchanged++
+		} else {
+			added = append(added, name)
+		}
+	}
+
+	for name := range have {
+		if !seen[name] {
+			stale++
+		}
+	}
+
+	return added, stale, unchanged
+}
+
+// Identity of a record across updates, its name and what it permits
+// The same name is emitted once per API, or once per path in strict mode
+func recordKey(r *cfg.Record) string {
+	key := r.PrimaryKey()
+	if permits, ok := r.Lookup("permit"); ok {
+		for _, t := range permits {
+			for _, a := range t.Attributes[1:] {
+				key += "\x00" + a.Name + "=" + a.Value
+			}
+		}
+	}
+
+	return key
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Placeholder values for -sample-values by string format
+var sampleFormats = map[string]string{
+	"email":     "example@example.com",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"hostname":  "example.com",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+	"uuid":      "00000000-0000-0000-0000-000000000000",
+	"date":      "1970-01-01",
+	"date-time": "1970-01-01T00:00:00Z",
+	"byte":      "AA==",
+}
+
+// Text of the value of the record for a parameter, marking sample values
+func valueText(api spec, u use) string {
+	v, sampled := value(api, u)
+	if commented(v) {
+		warn("warn: skipping value", v, "of", u.parameter.Name, "in the API", api.Info.Title, "as cfg reads # as a comment")
+		return ""
+	}
+	if sampled {
+		return v + " # sample"
+	}
+
+	return v
+}
+
+// Value of the record for a parameter and whether it is a -sample-values placeholder
+// Values from the environment, under -fill-from-env, take precedence over values from the spec, under -examples, then samples
+func value(api spec, u use) (string, bool) {
+	if v, ok := envValue(u); ok {
+		return clean(v), false
+	}
+
+	if v := specValue(api, u); v != "" {
+		return v, false
+	}
+
+	if *samples {
+		if v := sample(u); v != "" {
+			return clean(v), true
+		}
+	}
+
+	return "", false
+}
+
+// Identifiers already reported as filled by -fill-from-env
+var filled = make(map[string]bool)
+
+// Value of the environment variable for a parameter's identifier, under -fill-from-env
+// The variable is the -env-prefix then the identifier, uppercased with other than letters and digits as underscores
+func envValue(u use) (string, bool) {
+	if !*fromEnv {
+		return "", false
+	}
+
+	name := identifier(u)
+	key := *envPrefix + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+
+	v, ok := os.LookupEnv(key)
+	if ok && !filled[name] {
+		filled[name] = true
+		logLine("info", "info: filled", name, "from", key)
+	}
+
+	return v, ok
+}
+
+// Dummy value appropriate to a parameter's schema type and format
+func sample(u use) string {
+	schema := u.parameter.Schema
+	if len(schema.Enums) > 0 {
+		return schema.Enums[0]
+	}
+
+	raw, _ := u.raw["schema"].(map[string]interface{})
+	format, _ := raw["format"].(string)
+
+	switch schema.Type {
+	case "integer", "number":
+		return "0"
+
+	case "boolean":
+		return "true"
+
+	case "string":
+		if v, ok := sampleFormats[format]; ok {
+			return v
+		}
+		return "example"
+
+	case "array":
+		switch schema.Items.Type {
+		case "integer", "number":
+			return "0"
+		case "boolean":
+			return "true"
+		case "string":
+			return "example"
+		}
+
+	case "object":
+		return "{}"
+	}
+
+	return ""
+}
+
+// Value from the spec for a parameter, empty unless -examples is set
+// A named example is preferred, then the singular example, then the schema default
+func specValue(api spec, u use) string {
+	if !*examples {
+		return ""
+	}
+
+	if len(*exampleID) > 0 {
+		named, _ := u.raw["examples"].(map[string]interface{})
+		example, err := deref(api.doc, named[*exampleID])
+		if err != nil {
+			fatal("err: could not resolve example of", u.parameter.Name, "→", err)
+		}
+
+		if example, ok := example.(map[string]interface{}); ok {
+			if v, ok := example["value"]; ok {
+				return clean(literal(v))
+			}
+		} else {
+			var have []string
+			for name := range named {
+				have = append(have, name)
+			}
+			sort.Strings(have)
+			if len(have) < 1 {
+				have = append(have, "(none)")
+			}
+			info("info: no example", *exampleID, "for", u.parameter.Name, "of", u.path, "has:", strings.Join(have, " "))
+		}
+	}
+
+	if v, ok := u.raw["example"]; ok {
+		return clean(literal(v))
+	}
+
+	return clean(u.parameter.Schema.Default)
+}
+
+// Render a decoded JSON value as the text of a cfg value
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+
+	case string:
+		return v
+
+	case map[string]interface{}, []interface{}:
+		buf, _ := json.Marshal(v)
+		return string(buf)
+	}
+
+	return fmt.Sprint(v)
+}
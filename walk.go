@@ -0,0 +1,189 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/seh-msft/openapi"
+)
+
+// spec is a parsed API and the decoded document it was parsed from
+type spec struct {
+	openapi.API
+
+	// Decoded document, for fields openapi.API does not model
+	doc map[string]interface{}
+
+	// JSON Schema the API was loaded from, under -schema
+	root map[string]interface{}
+
+	// Row of the -batch-summary for the file the API was loaded from
+	summary *batchRow
+}
+
+// use is one parameter of one operation in an API
+type use struct {
+	path      string                 // Path the operation is served at
+	method    string                 // HTTP method of the operation, as keyed in the spec
+	operation openapi.Method         // The operation itself
+	index     int                    // Index of the parameter in the operation
+	parameter openapi.Parameter      // The parameter
+	raw       map[string]interface{} // Decoded parameter object
+	extension string                 // Vendor extension the parameter was harvested from, if any
+	pointer   string                 // JSON Pointer to the parameter in the spec
+
+	// oneOf or anyOf alternatives the body property appears in, under -explode-oneof
+	alternatives []string
+
+	// Schema the body property is defined in, if named
+	schema string
+}
+
+// Visit every parameter of an API which should be emitted, in a stable order
+func walk(api spec, fn func(u use)) {
+	survey(api, func(u use) {
+		if !u.parameter.Required && !*everything {
+			// Skip parameters that aren't required
+			return
+		}
+
+		fn(u)
+	})
+}
+
+// Visit every parameter of an API passing the filters, required or not
+func survey(api spec, fn func(u use)) {
+	if api.root != nil {
+		for _, u := range api.nested(api.root, "", true, "", nil) {
+			if filtered(u) {
+				fn(u)
+			}
+		}
+	}
+
+	var paths []string
+	for path := range api.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		var methods []string
+		for method := range api.Paths[path] {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			operation := api.Paths[path][method]
+			at := jsonPointer("paths", path, method)
+
+			var uses []use
+			for i, parameter := range operation.Parameters {
+				uses = append(uses, use{path: path, method: method, operation: operation, index: i, parameter: parameter, raw: api.rawParameter(path, method, i), pointer: at + jsonPointer("parameters", strconv.Itoa(i))})
+			}
+			uses = append(uses, api.harvest(path, method, operation)...)
+			uses = append(uses, api.body(path, method, operation)...)
+
+			for _, u := range uses {
+				if !filtered(u) {
+					continue
+				}
+
+				fn(u)
+			}
+		}
+	}
+}
+
+// Parameters carried by the -x-extensions of an operation
+// An array holds parameter objects, an object maps names to values, and a scalar is named by its key
+func (api spec) harvest(path, method string, operation openapi.Method) []use {
+	if len(*extensions) < 1 {
+		return nil
+	}
+
+	paths, _ := api.doc["paths"].(map[string]interface{})
+	methods, _ := paths[path].(map[string]interface{})
+	raw, _ := methods[method].(map[string]interface{})
+
+	at := jsonPointer("paths", path, method)
+
+	var uses []use
+	for _, key := range strings.Split(*extensions, ",") {
+		key = strings.TrimSpace(key)
+		ext, ok := raw[key]
+		if !ok {
+			continue
+		}
+
+		var objects []map[string]interface{}
+		var pointers []string
+		switch ext := ext.(type) {
+		case []interface{}:
+			for i, e := range ext {
+				if obj, ok := e.(map[string]interface{}); ok {
+					objects = append(objects, obj)
+					pointers = append(pointers, at+jsonPointer(key, strconv.Itoa(i)))
+				}
+			}
+
+		case map[string]interface{}:
+			var names []string
+			for name := range ext {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				objects = append(objects, map[string]interface{}{"name": name, "in": key, "required": true, "example": ext[name]})
+				pointers = append(pointers, at+jsonPointer(key, name))
+			}
+
+		default:
+			objects = append(objects, map[string]interface{}{"name": key, "in": key, "required": true, "example": ext})
+			pointers = append(pointers, at+jsonPointer(key))
+		}
+
+		for i, obj := range objects {
+			var parameter openapi.Parameter
+			buf, _ := json.Marshal(obj)
+			if err := json.Unmarshal(buf, &parameter); err != nil {
+				fatal("err: could not parse", key, "of", strings.ToUpper(method), path, "→", err)
+			}
+
+			uses = append(uses, use{path: path, method: method, operation: operation, index: i, parameter: parameter, raw: obj, extension: key, pointer: pointers[i]})
+		}
+	}
+
+	return uses
+}
+
+// Decoded object of a parameter, for fields openapi.Parameter does not model
+func (api spec) rawParameter(path, method string, i int) map[string]interface{} {
+	paths, _ := api.doc["paths"].(map[string]interface{})
+	methods, _ := paths[path].(map[string]interface{})
+	operation, _ := methods[method].(map[string]interface{})
+	parameters, _ := operation["parameters"].([]interface{})
+	if i >= len(parameters) {
+		return nil
+	}
+
+	parameter, _ := parameters[i].(map[string]interface{})
+	return parameter
+}
+
+// Join tokens into a JSON Pointer, escaping each
+func jsonPointer(tokens ...string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString("/" + strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2021, Microsoft Corporation, Sean Hinchee
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// endpoint is one place an identifier is used
+type endpoint struct {
+	API     string `json:"api"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Pointer string `json:"pointer,omitempty"` // Under -with-pointer
+}
+
+// Emit a JSON index of each identifier to the endpoints using it
+func xrefs(apis []spec, out io.Writer) {
+	index := make(map[string][]endpoint)
+	for _, api := range apis {
+		walk(api, func(u use) {
+			name := identifier(u)
+			e := endpoint{API: api.Info.Title, Method: strings.ToUpper(u.method), Path: u.path}
+			if *withPtr {
+				e.Pointer = u.pointer
+			}
+			index[name] = append(index[name], e)
+		})
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(index); err != nil {
+		fatal("err: could not encode to JSON →", err)
+	}
+}